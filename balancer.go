@@ -0,0 +1,63 @@
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+)
+
+// ErrNoAvailableClient is returned by a Balancer, and by Pool, when
+// the Pool has no member client to serve a call.
+var ErrNoAvailableClient = errors.New("rpcx: no available client")
+
+// Balancer selects one of a Pool's current member clients to serve a
+// call. Implementations must be safe for concurrent use.
+type Balancer interface {
+	// Pick returns a client to use for serviceMethod. clients is the
+	// Pool's current snapshot of member clients and must not be
+	// retained or mutated. Members evicted by the Pool's heartbeat are
+	// left out, but a member whose connection is not Ready is not.
+	Pick(ctx context.Context, serviceMethod string, clients []*Client) (*Client, error)
+}
+
+// RoundRobin picks clients in rotation.
+type RoundRobin struct {
+	next uint64
+}
+
+func (b *RoundRobin) Pick(ctx context.Context, serviceMethod string, clients []*Client) (*Client, error) {
+	if len(clients) == 0 {
+		return nil, ErrNoAvailableClient
+	}
+	i := atomic.AddUint64(&b.next, 1) - 1
+	return clients[i%uint64(len(clients))], nil
+}
+
+// Random picks a client uniformly at random.
+type Random struct{}
+
+func (Random) Pick(ctx context.Context, serviceMethod string, clients []*Client) (*Client, error) {
+	if len(clients) == 0 {
+		return nil, ErrNoAvailableClient
+	}
+	return clients[rand.Intn(len(clients))], nil
+}
+
+// LeastPending picks the client with the fewest in-flight synchronous
+// calls, falling back to the first client on ties.
+type LeastPending struct{}
+
+func (LeastPending) Pick(ctx context.Context, serviceMethod string, clients []*Client) (*Client, error) {
+	if len(clients) == 0 {
+		return nil, ErrNoAvailableClient
+	}
+	best := clients[0]
+	bestPending := atomic.LoadInt64(&best.pending)
+	for _, c := range clients[1:] {
+		if p := atomic.LoadInt64(&c.pending); p < bestPending {
+			best, bestPending = c, p
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,136 @@
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+	"time"
+)
+
+// ErrUnknownClass is returned by CallClass/GoClass for a class not
+// passed to WithConnectionClasses.
+var ErrUnknownClass = errors.New("rpcx: unknown connection class")
+
+// ClassConfig configures one named connection class dialed alongside a
+// Client's default connection via WithConnectionClasses. PingServiceMethod,
+// left empty, means the class connection has no heartbeat of its own.
+// Fallback names another class to route calls to while this one is not
+// Ready, e.g. routing "priority" calls over "control" during a reconnect.
+type ClassConfig struct {
+	PingServiceMethod string
+	PingInterval      time.Duration
+	PingHandler       PingHandler
+	Fallback          string
+}
+
+// WithConnectionClasses dials one additional *Client connection per
+// entry in classes, alongside the Client's own default connection.
+// Client.CallClass/GoClass route a call over a named class's connection
+// instead of the default one, so a slow call on one class (e.g. "bulk")
+// cannot head-of-line-block a latency-sensitive call on another (e.g.
+// "control") over Go's single-writer net/rpc connection. Every class
+// shares the Client's network, address, transport, logger and
+// interceptors, but has its own connection, heartbeat and reconnect
+// state.
+func WithConnectionClasses(classes map[string]ClassConfig) DialOption {
+	return func(o *dialOptions) {
+		o.classes = classes
+	}
+}
+
+// dialClasses dials one *Client per entry in client.dialOptions.classes,
+// reusing Dial itself so each class gets the same reconnect, heartbeat
+// and state machinery as the default connection.
+func (client *Client) dialClasses() {
+	if len(client.dialOptions.classes) == 0 {
+		return
+	}
+	client.classes = make(map[string]*Client, len(client.dialOptions.classes))
+	for name, cfg := range client.dialOptions.classes {
+		client.classes[name] = client.dialClass(name, cfg)
+	}
+}
+
+func (client *Client) dialClass(name string, cfg ClassConfig) *Client {
+	sharedOpts := []DialOption{
+		WithTransport(client.dialOptions.transport),
+		WithLogger(client.dialOptions.logger),
+		WithReconnectBackoff(client.dialOptions.backoff),
+	}
+	if client.dialOptions.timeout > 0 {
+		// WithTimeout(0) means "5s", not "unset"; only share an
+		// explicit timeout so a class doesn't pick up one the parent
+		// Client never asked for.
+		sharedOpts = append(sharedOpts, WithTimeout(client.dialOptions.timeout))
+	}
+	if client.dialOptions.callInterceptor != nil {
+		sharedOpts = append(sharedOpts, WithCallInterceptor(client.dialOptions.callInterceptor))
+	}
+	if client.dialOptions.goInterceptor != nil {
+		sharedOpts = append(sharedOpts, WithGoInterceptor(client.dialOptions.goInterceptor))
+	}
+	if cfg.PingServiceMethod != "" {
+		sharedOpts = append(sharedOpts, WithHeartbeat(cfg.PingServiceMethod, cfg.PingInterval, cfg.PingHandler))
+	}
+
+	opts := sharedOpts
+	if client.dialOptions.block {
+		opts = append(opts, WithBlock())
+	}
+
+	c, err := Dial(client.dialOptions.network, client.dialOptions.address, opts...)
+	if err != nil {
+		// Only WithBlock can fail synchronously; fall back to a
+		// non-blocking dial so the class still comes up and reconnects
+		// in the background.
+		client.dialOptions.logger.Error("class: dial failed", "class", name, "error", err)
+		c, _ = Dial(client.dialOptions.network, client.dialOptions.address, sharedOpts...)
+	}
+	return c
+}
+
+// resolveClass returns the *Client to use for class, following its
+// ClassConfig.Fallback when the class's own connection is not Ready.
+func (client *Client) resolveClass(class string) (*Client, error) {
+	target, ok := client.classes[class]
+	if !ok {
+		return nil, ErrUnknownClass
+	}
+	if target.State() == Ready {
+		return target, nil
+	}
+	if fallback := client.dialOptions.classes[class].Fallback; fallback != "" {
+		if c, ok := client.classes[fallback]; ok && c.State() == Ready {
+			return c, nil
+		}
+	}
+	return target, nil
+}
+
+// CallClass issues a synchronous call over the named class's
+// connection instead of the Client's default connection.
+func (client *Client) CallClass(ctx context.Context, class, serviceMethod string, args, reply interface{}) error {
+	target, err := client.resolveClass(class)
+	if err != nil {
+		return err
+	}
+	return target.CallContext(ctx, serviceMethod, args, reply)
+}
+
+// GoClass issues an asynchronous call over the named class's
+// connection instead of the Client's default connection.
+func (client *Client) GoClass(ctx context.Context, class, serviceMethod string, args, reply interface{}) *rpc.Call {
+	target, err := client.resolveClass(class)
+	if err != nil {
+		call := &rpc.Call{
+			ServiceMethod: serviceMethod,
+			Args:          args,
+			Reply:         reply,
+			Error:         err,
+			Done:          make(chan *rpc.Call, 1), // buffered.
+		}
+		call.Done <- call
+		return call
+	}
+	return target.GoContext(ctx, serviceMethod, args, reply)
+}
@@ -2,11 +2,7 @@ package rpcx
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"net"
 	"net/rpc"
-	"os"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -24,6 +20,16 @@ type Client struct {
 	closed           bool           // user has called Close
 
 	dialOptions dialOptions
+
+	pending int64 // number of in-flight synchronous calls, read by balancers such as LeastPending
+
+	state         int32 // atomic, see State
+	stateMutex    sync.Mutex
+	stateChangeCh chan struct{} // closed and replaced every time state changes
+
+	reconnectCh chan struct{} // buffered 1; see triggerReconnect
+
+	classes map[string]*Client // set once in Dial by dialClasses, read-only afterward; see WithConnectionClasses
 }
 
 func (client *Client) getClient() *rpc.Client {
@@ -40,11 +46,14 @@ type dialOptions struct {
 	timeout           time.Duration
 	block             bool
 	logger            Logger
+	transport         Transport
+	backoff           BackoffPolicy
 	pingServiceMethod string
 	pingInterval      time.Duration
 	pingHandler       PingHandler
 	callInterceptor   CallInterceptor
 	goInterceptor     GoInterceptor
+	classes           map[string]ClassConfig
 }
 
 type DialOption func(*dialOptions)
@@ -71,27 +80,6 @@ func WithBlock() DialOption {
 	}
 }
 
-var defaultLogger Logger = (*logger)(log.New(os.Stderr, "", log.Ldate|log.Ltime|log.Llongfile))
-
-type logger log.Logger
-
-func (l *logger) Errorf(format string, v ...interface{}) {
-	(*log.Logger)(l).Output(2, fmt.Sprintf(format, v...))
-}
-
-type Logger interface {
-	Errorf(format string, v ...interface{})
-}
-
-func WithLogger(logger Logger) DialOption {
-	return func(o *dialOptions) {
-		if logger == nil {
-			return
-		}
-		o.logger = logger
-	}
-}
-
 type PingHandler func(pingResult error, client *Client)
 
 func WithHeartbeat(pingServiceMethod string, interval time.Duration, handler PingHandler) DialOption {
@@ -123,6 +111,36 @@ func WithCallInterceptor(interceptor CallInterceptor) DialOption {
 	}
 }
 
+// WithCallInterceptors composes interceptors into a single CallInterceptor,
+// chaining them left-to-right so the first interceptor is outermost and
+// runs its logic before handing off to the next, and installs it exactly
+// as WithCallInterceptor would. It is the multi-interceptor counterpart
+// to WithCallInterceptor, e.g. for layering middleware such as a retry
+// interceptor around a circuit breaker.
+func WithCallInterceptors(interceptors ...CallInterceptor) DialOption {
+	return WithCallInterceptor(chainCallInterceptors(interceptors))
+}
+
+func chainCallInterceptors(interceptors []CallInterceptor) CallInterceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+	return func(ctx context.Context, serviceMethod string, args, reply interface{}, invoker CallInvoker) error {
+		chained := invoker
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor := interceptors[i]
+			next := chained
+			chained = func(ctx context.Context, serviceMethod string, args, reply interface{}) error {
+				return interceptor(ctx, serviceMethod, args, reply, next)
+			}
+		}
+		return chained(ctx, serviceMethod, args, reply)
+	}
+}
+
 type GoInvoker func(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) *rpc.Call
 type GoInterceptor func(ctx context.Context, serviceMethod string, args interface{}, reply interface{}, invoker GoInvoker) *rpc.Call
 
@@ -135,6 +153,34 @@ func WithGoInterceptor(interceptor GoInterceptor) DialOption {
 	}
 }
 
+// WithGoInterceptors composes interceptors into a single GoInterceptor,
+// chaining them left-to-right, and installs it exactly as
+// WithGoInterceptor would. It is the asynchronous counterpart to
+// WithCallInterceptors.
+func WithGoInterceptors(interceptors ...GoInterceptor) DialOption {
+	return WithGoInterceptor(chainGoInterceptors(interceptors))
+}
+
+func chainGoInterceptors(interceptors []GoInterceptor) GoInterceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+	return func(ctx context.Context, serviceMethod string, args, reply interface{}, invoker GoInvoker) *rpc.Call {
+		chained := invoker
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor := interceptors[i]
+			next := chained
+			chained = func(ctx context.Context, serviceMethod string, args, reply interface{}) *rpc.Call {
+				return interceptor(ctx, serviceMethod, args, reply, next)
+			}
+		}
+		return chained(ctx, serviceMethod, args, reply)
+	}
+}
+
 func Dial(network, address string, opts ...DialOption) (*Client, error) {
 	var client Client
 	opts = append(opts, withNetworkAddress(network, address))
@@ -144,23 +190,34 @@ func Dial(network, address string, opts ...DialOption) (*Client, error) {
 	if client.dialOptions.logger == nil {
 		WithLogger(defaultLogger)(&client.dialOptions)
 	}
+	if client.dialOptions.transport == nil {
+		WithTransport(tcpTransport{})(&client.dialOptions)
+	}
+	if client.dialOptions.backoff == (BackoffPolicy{}) {
+		WithReconnectBackoff(BackoffPolicy{})(&client.dialOptions)
+	}
 	client.closeCanBeCalled = true
+	client.stateChangeCh = make(chan struct{})
+	client.reconnectCh = make(chan struct{}, 1)
 
 	if client.dialOptions.block {
+		// Don't start reconnectLoop until the blocking dial has
+		// succeeded: on failure we return the error directly and
+		// there must be no goroutine left parked on reconnectCh.
 		if err := client.Reset(); err != nil {
 			return nil, err
 		}
+		client.setState(Ready)
+		go client.reconnectLoop()
 	} else {
-		go func() {
-			if err := client.Reset(); err != nil {
-				client.dialOptions.logger.Errorf("[error][rpcx]: Reset: %s", err.Error())
-				return
-			}
-		}()
+		client.setState(Connecting)
+		go client.reconnectLoop()
+		client.triggerReconnect()
 	}
 	if client.dialOptions.pingServiceMethod != "" && client.dialOptions.pingInterval > 0 {
 		go client.monitor()
 	}
+	client.dialClasses()
 	return &client, nil
 }
 
@@ -189,14 +246,11 @@ func defaultPingHandler(result error, client *Client) {
 	if result == nil {
 		return
 	}
-	if result != rpc.ErrShutdown {
-		client.dialOptions.logger.Errorf("[error][rpcx]: ping: %s", result.Error())
-		return
-	}
-	if err := client.Reset(); err != nil {
-		client.dialOptions.logger.Errorf("[error][rpcx]: Reset: %s", err.Error())
+	if !isConnError(result) {
+		client.dialOptions.logger.Warn("ping failed", "address", client.dialOptions.address, "error", result)
 		return
 	}
+	client.triggerReconnect()
 }
 
 func (client *Client) ping() error {
@@ -219,12 +273,13 @@ func (client *Client) Reset() error {
 			return err
 		}
 	}
-	dialer := net.Dialer{
-		Timeout:   client.dialOptions.timeout,
-		KeepAlive: 30 * time.Second,
-		DualStack: true,
+	ctx := context.Background()
+	if client.dialOptions.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.dialOptions.timeout)
+		defer cancel()
 	}
-	conn, err := dialer.Dial(client.dialOptions.network, client.dialOptions.address)
+	conn, err := client.dialOptions.transport.Dial(ctx, client.dialOptions.network, client.dialOptions.address)
 	if err != nil {
 		return err
 	}
@@ -235,15 +290,25 @@ func (client *Client) Reset() error {
 
 func (client *Client) Close() error {
 	client.mutex.Lock()
-	defer client.mutex.Unlock()
-
+	alreadyClosed := client.closed
 	client.closed = true
 	rpcClient := client.getClient()
+	closeCanBeCalled := client.closeCanBeCalled
+	client.closeCanBeCalled = true // next time can be called, compatible with net/rpc.Client.Close
+	client.mutex.Unlock()
+
+	if !alreadyClosed {
+		client.setState(Shutdown)
+		client.triggerReconnect() // wake reconnectLoop so it observes client.closed and exits
+		for _, c := range client.classes {
+			c.Close()
+		}
+	}
+
 	if rpcClient == nil {
 		return rpc.ErrShutdown
 	}
-	if !client.closeCanBeCalled {
-		client.closeCanBeCalled = true // next time can be called, compatible with net/rpc.Client.Close
+	if !closeCanBeCalled {
 		return nil
 	}
 	return rpcClient.Close()
@@ -258,6 +323,9 @@ func (client *Client) Call(serviceMethod string, args interface{}, reply interfa
 }
 
 func (client *Client) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	atomic.AddInt64(&client.pending, 1)
+	defer atomic.AddInt64(&client.pending, -1)
+
 	if interceptor := client.dialOptions.callInterceptor; interceptor != nil {
 		ctx = peer.NewContext(ctx, client.dialOptions.address)
 		return interceptor(ctx, serviceMethod, args, reply, client.callContext)
@@ -268,18 +336,25 @@ func (client *Client) CallContext(ctx context.Context, serviceMethod string, arg
 func (client *Client) callContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
 	rpcClient := client.getClient()
 	if rpcClient == nil {
+		client.triggerReconnect()
 		return rpc.ErrShutdown
 	}
+	var err error
 	if ctx == context.Background() {
 		call := <-rpcClient.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1)).Done
-		return call.Error
+		err = call.Error
+	} else {
+		select {
+		case call := <-rpcClient.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1)).Done:
+			err = call.Error
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	select {
-	case call := <-rpcClient.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1)).Done:
-		return call.Error
-	case <-ctx.Done():
-		return ctx.Err()
+	if isConnError(err) {
+		client.triggerReconnect()
 	}
+	return err
 }
 
 func (client *Client) Go(serviceMethod string, args interface{}, reply interface{}) *rpc.Call {
@@ -297,6 +372,7 @@ func (client *Client) GoContext(ctx context.Context, serviceMethod string, args
 func (client *Client) goContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) *rpc.Call {
 	rpcClient := client.getClient()
 	if rpcClient == nil {
+		client.triggerReconnect()
 		call := &rpc.Call{
 			ServiceMethod: serviceMethod,
 			Args:          args,
@@ -308,11 +384,11 @@ func (client *Client) goContext(ctx context.Context, serviceMethod string, args
 		return call
 	}
 	if ctx == context.Background() {
-		return rpcClient.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1))
+		return client.goWatched(rpcClient, serviceMethod, args, reply)
 	}
 	done := make(chan *rpc.Call, 1) // buffered.
 	go func() {
-		done <- rpcClient.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1))
+		done <- client.goWatched(rpcClient, serviceMethod, args, reply)
 	}()
 	select {
 	case call := <-done:
@@ -329,3 +405,28 @@ func (client *Client) goContext(ctx context.Context, serviceMethod string, args
 		return call
 	}
 }
+
+// goWatched issues an asynchronous call over rpcClient and returns a
+// *rpc.Call whose Done channel fires once, like rpc.Client.Go, but
+// additionally triggers a reconnect if the call fails with a
+// connection-level error.
+func (client *Client) goWatched(rpcClient *rpc.Client, serviceMethod string, args, reply interface{}) *rpc.Call {
+	internal := make(chan *rpc.Call, 1) // the only reader of this channel is below; never exposed to the caller
+	rpcClient.Go(serviceMethod, args, reply, internal)
+
+	public := &rpc.Call{
+		ServiceMethod: serviceMethod,
+		Args:          args,
+		Reply:         reply,
+		Done:          make(chan *rpc.Call, 1), // buffered.
+	}
+	go func() {
+		result := <-internal
+		if isConnError(result.Error) {
+			client.triggerReconnect()
+		}
+		public.Error = result.Error
+		public.Done <- public
+	}()
+	return public
+}
@@ -0,0 +1,125 @@
+package rpcx
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// Logger is rpcx's structured, leveled logging interface. Debug, Info,
+// Warn and Error take a short message plus alternating key-value pairs,
+// mirroring log/slog. With returns a Logger that carries kv into every
+// subsequent call, for attaching request-scoped context such as an
+// address or attempt count without threading it through every call
+// site.
+type Logger interface {
+	Debug(msg string, kv ...interface{})
+	Info(msg string, kv ...interface{})
+	Warn(msg string, kv ...interface{})
+	Error(msg string, kv ...interface{})
+	With(kv ...interface{}) Logger
+}
+
+// errorfer is the shape of rpcx's original, Errorf-only Logger.
+// LegacyLogger adapts one to Logger, so loggers written against the old
+// interface keep working unmodified.
+type errorfer interface {
+	Errorf(format string, v ...interface{})
+}
+
+// WithLogger selects the Logger a Client logs to, in place of
+// defaultLogger.
+func WithLogger(logger Logger) DialOption {
+	return func(o *dialOptions) {
+		if logger == nil {
+			return
+		}
+		o.logger = logger
+	}
+}
+
+// LegacyLogger adapts an Errorf-only logger, the shape rpcx's original
+// Logger had, to the current structured, leveled Logger interface, for
+// passing to WithLogger unmodified.
+func LegacyLogger(e errorfer) Logger {
+	return errorfShim{l: e}
+}
+
+// NoopLogger discards everything logged to it.
+var NoopLogger Logger = noopLogger{}
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+func (noopLogger) With(...interface{}) Logger   { return noopLogger{} }
+
+// NewSlogLogger adapts an *slog.Logger to Logger.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+type slogLogger struct{ l *slog.Logger }
+
+func (s slogLogger) Debug(msg string, kv ...interface{}) { s.l.Debug(msg, kv...) }
+func (s slogLogger) Info(msg string, kv ...interface{})  { s.l.Info(msg, kv...) }
+func (s slogLogger) Warn(msg string, kv ...interface{})  { s.l.Warn(msg, kv...) }
+func (s slogLogger) Error(msg string, kv ...interface{}) { s.l.Error(msg, kv...) }
+func (s slogLogger) With(kv ...interface{}) Logger       { return slogLogger{l: s.l.With(kv...)} }
+
+// errorfShim adapts an errorfer to Logger: Warn and Error are
+// forwarded to Errorf, since that is the closest the old interface
+// gets to either; Debug and Info, which the old interface had no way
+// to express, are dropped rather than misreported as errors.
+type errorfShim struct {
+	l      errorfer
+	fields []interface{}
+}
+
+func (s errorfShim) Debug(msg string, kv ...interface{}) {}
+func (s errorfShim) Info(msg string, kv ...interface{})  {}
+
+func (s errorfShim) Warn(msg string, kv ...interface{}) {
+	s.l.Errorf("%s", formatKV(msg, append(append([]interface{}{}, s.fields...), kv...)))
+}
+
+func (s errorfShim) Error(msg string, kv ...interface{}) {
+	s.l.Errorf("%s", formatKV(msg, append(append([]interface{}{}, s.fields...), kv...)))
+}
+
+func (s errorfShim) With(kv ...interface{}) Logger {
+	return errorfShim{l: s.l, fields: append(append([]interface{}{}, s.fields...), kv...)}
+}
+
+// defaultLogger is the Logger used when WithLogger is not given; it
+// writes to os.Stderr in the same format the package has always used.
+var defaultLogger Logger = &stdLogger{l: log.New(os.Stderr, "", log.Ldate|log.Ltime|log.Llongfile)}
+
+type stdLogger struct {
+	l      *log.Logger
+	fields []interface{}
+}
+
+func (s *stdLogger) Debug(msg string, kv ...interface{}) { s.output("debug", msg, kv) }
+func (s *stdLogger) Info(msg string, kv ...interface{})  { s.output("info", msg, kv) }
+func (s *stdLogger) Warn(msg string, kv ...interface{})  { s.output("warn", msg, kv) }
+func (s *stdLogger) Error(msg string, kv ...interface{}) { s.output("error", msg, kv) }
+
+func (s *stdLogger) With(kv ...interface{}) Logger {
+	return &stdLogger{l: s.l, fields: append(append([]interface{}{}, s.fields...), kv...)}
+}
+
+func (s *stdLogger) output(level, msg string, kv []interface{}) {
+	all := append(append([]interface{}{}, s.fields...), kv...)
+	s.l.Output(3, fmt.Sprintf("[%s][rpcx]: %s", level, formatKV(msg, all)))
+}
+
+func formatKV(msg string, kv []interface{}) string {
+	for i := 0; i+1 < len(kv); i += 2 {
+		msg += fmt.Sprintf(" %v=%v", kv[i], kv[i+1])
+	}
+	return msg
+}
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/chanxuehong/rpcx"
+)
+
+// ErrCircuitOpen is returned by CircuitBreaker's CallInterceptor in place
+// of invoking the call while the breaker for that serviceMethod is open.
+var ErrCircuitOpen = errors.New("middleware: circuit breaker open")
+
+// CircuitBreakerConfig configures a CircuitBreaker.
+type CircuitBreakerConfig struct {
+	// Window is the length of the fixed window over which failures and
+	// successes are counted: the counts start at the first request and
+	// are reset, all at once, by the first request recorded more than
+	// Window later. The window does not slide. Defaults to 10s.
+	Window time.Duration
+	// MinRequests is the minimum number of requests in Window before
+	// FailureRatio is evaluated at all. Defaults to 10.
+	MinRequests int
+	// FailureRatio, once MinRequests is met, trips the breaker open when
+	// exceeded. Defaults to 0.5.
+	FailureRatio float64
+	// OpenDuration is how long the breaker stays open before allowing a
+	// single half-open probe request through. Defaults to 5s.
+	OpenDuration time.Duration
+}
+
+func (c *CircuitBreakerConfig) setDefaults() {
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 10
+	}
+	if c.FailureRatio <= 0 {
+		c.FailureRatio = 0.5
+	}
+	if c.OpenDuration <= 0 {
+		c.OpenDuration = 5 * time.Second
+	}
+}
+
+// CircuitBreaker tracks a failure ratio over a fixed window per serviceMethod
+// and, once a method is failing badly enough, short-circuits further
+// calls to it for OpenDuration before letting a single half-open probe
+// through to test recovery. Unlike the other interceptors in this
+// package, which are plain functions, CircuitBreaker holds state and
+// must be constructed with NewCircuitBreaker.
+type CircuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	mutex    sync.Mutex
+	breakers map[string]*breakerState
+}
+
+type breakerState struct {
+	windowStart time.Time
+	requests    int
+	failures    int
+
+	open      bool
+	openUntil time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker constructs a CircuitBreaker from cfg, filling in
+// zero-valued fields with their defaults.
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	cfg.setDefaults()
+	return &CircuitBreaker{
+		cfg:      cfg,
+		breakers: make(map[string]*breakerState),
+	}
+}
+
+// CallInterceptor returns the CallInterceptor backed by cb, for use with
+// rpcx.WithCallInterceptor or rpcx.WithCallInterceptors.
+func (cb *CircuitBreaker) CallInterceptor() rpcx.CallInterceptor {
+	return func(ctx context.Context, serviceMethod string, args, reply interface{}, invoker rpcx.CallInvoker) error {
+		probe, err := cb.allow(serviceMethod)
+		if err != nil {
+			return err
+		}
+		callErr := invoker(ctx, serviceMethod, args, reply)
+		cb.record(serviceMethod, probe, callErr == nil)
+		return callErr
+	}
+}
+
+// GoInterceptor returns the GoInterceptor backed by cb, for use with
+// rpcx.WithGoInterceptor or rpcx.WithGoInterceptors. It is
+// CallInterceptor's asynchronous counterpart: the breaker still records
+// the outcome against serviceMethod's window, just once the call
+// completes rather than before GoInterceptor returns, relaying the
+// result through a public *rpc.Call the same way TimeoutGo does.
+func (cb *CircuitBreaker) GoInterceptor() rpcx.GoInterceptor {
+	return func(ctx context.Context, serviceMethod string, args, reply interface{}, invoker rpcx.GoInvoker) *rpc.Call {
+		probe, err := cb.allow(serviceMethod)
+		if err != nil {
+			call := &rpc.Call{
+				ServiceMethod: serviceMethod,
+				Args:          args,
+				Reply:         reply,
+				Error:         err,
+				Done:          make(chan *rpc.Call, 1), // buffered.
+			}
+			call.Done <- call
+			return call
+		}
+		inner := invoker(ctx, serviceMethod, args, reply)
+
+		public := &rpc.Call{
+			ServiceMethod: serviceMethod,
+			Args:          args,
+			Reply:         reply,
+			Done:          make(chan *rpc.Call, 1), // buffered.
+		}
+		go func() {
+			result := <-inner.Done
+			cb.record(serviceMethod, probe, result.Error == nil)
+			public.Error = result.Error
+			public.Done <- public
+		}()
+		return public
+	}
+}
+
+func (cb *CircuitBreaker) allow(serviceMethod string) (probe bool, err error) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	s := cb.state(serviceMethod)
+	now := time.Now()
+	if s.open {
+		if now.Before(s.openUntil) {
+			return false, ErrCircuitOpen
+		}
+		if s.probing {
+			return false, ErrCircuitOpen
+		}
+		s.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+func (cb *CircuitBreaker) record(serviceMethod string, probe, success bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	s := cb.state(serviceMethod)
+	if probe {
+		s.probing = false
+		if success {
+			*s = breakerState{windowStart: time.Now()}
+		} else {
+			s.openUntil = time.Now().Add(cb.cfg.OpenDuration)
+		}
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(s.windowStart) > cb.cfg.Window {
+		s.windowStart = now
+		s.requests = 0
+		s.failures = 0
+	}
+	s.requests++
+	if !success {
+		s.failures++
+	}
+	if s.requests >= cb.cfg.MinRequests && float64(s.failures)/float64(s.requests) > cb.cfg.FailureRatio {
+		s.open = true
+		s.openUntil = now.Add(cb.cfg.OpenDuration)
+	}
+}
+
+// state returns serviceMethod's breakerState, creating it if necessary.
+// Callers must hold cb.mutex.
+func (cb *CircuitBreaker) state(serviceMethod string) *breakerState {
+	s, ok := cb.breakers[serviceMethod]
+	if !ok {
+		s = &breakerState{windowStart: time.Now()}
+		cb.breakers[serviceMethod] = s
+	}
+	return s
+}
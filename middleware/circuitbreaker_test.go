@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+	"testing"
+	"time"
+
+	"github.com/chanxuehong/rpcx"
+)
+
+var errBoom = errors.New("boom")
+
+func callThrough(intercept rpcx.CallInterceptor, fail bool) error {
+	return callThroughDelayed(intercept, fail, 0)
+}
+
+func callThroughDelayed(intercept rpcx.CallInterceptor, fail bool, delay time.Duration) error {
+	invoker := func(ctx context.Context, serviceMethod string, args, reply interface{}) error {
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		if fail {
+			return errBoom
+		}
+		return nil
+	}
+	return intercept(context.Background(), "Svc.Method", nil, nil, invoker)
+}
+
+func TestCircuitBreakerTripsOpenAndHalfOpens(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Window:       time.Second,
+		MinRequests:  2,
+		FailureRatio: 0.5,
+		OpenDuration: 30 * time.Millisecond,
+	})
+	intercept := cb.CallInterceptor()
+
+	// Two failing requests meet MinRequests and exceed FailureRatio: the
+	// breaker should trip open.
+	if err := callThrough(intercept, true); err != errBoom {
+		t.Fatalf("call 1: got %v, want errBoom", err)
+	}
+	if err := callThrough(intercept, true); err != errBoom {
+		t.Fatalf("call 2: got %v, want errBoom", err)
+	}
+
+	if err := callThrough(intercept, false); err != ErrCircuitOpen {
+		t.Fatalf("call while open: got %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(40 * time.Millisecond) // let OpenDuration elapse
+
+	// The first call after OpenDuration is the half-open probe; a
+	// concurrent call must still be rejected while it's in flight.
+	probeDone := make(chan struct{})
+	go func() {
+		defer close(probeDone)
+		if err := callThroughDelayed(intercept, false, 20*time.Millisecond); err != nil {
+			t.Errorf("probe call: got %v, want nil", err)
+		}
+	}()
+	time.Sleep(5 * time.Millisecond) // let the probe above claim allow()'s single slot first
+	if err := callThrough(intercept, false); err != ErrCircuitOpen {
+		t.Fatalf("call racing the probe: got %v, want ErrCircuitOpen", err)
+	}
+	<-probeDone
+
+	// The probe succeeded, so the breaker should be closed again.
+	if err := callThrough(intercept, false); err != nil {
+		t.Fatalf("call after probe success: got %v, want nil", err)
+	}
+}
+
+func TestCircuitBreakerReopensOnFailedProbe(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Window:       time.Second,
+		MinRequests:  1,
+		FailureRatio: 0,
+		OpenDuration: 20 * time.Millisecond,
+	})
+	intercept := cb.CallInterceptor()
+
+	if err := callThrough(intercept, true); err != errBoom {
+		t.Fatalf("tripping call: got %v, want errBoom", err)
+	}
+	if err := callThrough(intercept, false); err != ErrCircuitOpen {
+		t.Fatalf("call while open: got %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if err := callThrough(intercept, true); err != errBoom {
+		t.Fatalf("failed probe: got %v, want errBoom", err)
+	}
+	if err := callThrough(intercept, false); err != ErrCircuitOpen {
+		t.Fatalf("call right after a failed probe: got %v, want ErrCircuitOpen (breaker should stay open)", err)
+	}
+}
+
+func TestCircuitBreakerGoInterceptorRecordsOutcome(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Window:       time.Second,
+		MinRequests:  1,
+		FailureRatio: 0,
+		OpenDuration: time.Hour,
+	})
+	goIntercept := cb.GoInterceptor()
+
+	failingInvoker := func(ctx context.Context, serviceMethod string, args, reply interface{}) *rpc.Call {
+		call := &rpc.Call{ServiceMethod: serviceMethod, Args: args, Reply: reply, Done: make(chan *rpc.Call, 1)}
+		go func() {
+			call.Error = errBoom
+			call.Done <- call
+		}()
+		return call
+	}
+	call := goIntercept(context.Background(), "Svc.Method", nil, nil, failingInvoker)
+	<-call.Done
+	if call.Error != errBoom {
+		t.Fatalf("call.Error = %v, want errBoom", call.Error)
+	}
+
+	// The failure above should have been recorded against the breaker,
+	// so the very next call - regardless of whether it would itself
+	// fail - finds the circuit already open.
+	succeedingInvoker := func(ctx context.Context, serviceMethod string, args, reply interface{}) *rpc.Call {
+		call := &rpc.Call{ServiceMethod: serviceMethod, Args: args, Reply: reply, Done: make(chan *rpc.Call, 1)}
+		call.Done <- call
+		return call
+	}
+	call = goIntercept(context.Background(), "Svc.Method", nil, nil, succeedingInvoker)
+	<-call.Done
+	if call.Error != ErrCircuitOpen {
+		t.Fatalf("call.Error = %v, want ErrCircuitOpen", call.Error)
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"net/rpc"
+	"time"
+
+	"github.com/chanxuehong/rpcx"
+)
+
+// DefaultDeadline returns a CallInterceptor that applies d as the call's
+// deadline only when ctx does not already carry one, so a caller's own
+// context.WithTimeout/WithDeadline always takes precedence. Unlike
+// Timeout, which always shortens the call to d, DefaultDeadline only
+// fills in a deadline callers forgot to set, and is typically installed
+// outermost in a chain built with rpcx.WithCallInterceptors so every
+// call reaching the network has one.
+func DefaultDeadline(d time.Duration) rpcx.CallInterceptor {
+	return func(ctx context.Context, serviceMethod string, args, reply interface{}, invoker rpcx.CallInvoker) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+		return invoker(ctx, serviceMethod, args, reply)
+	}
+}
+
+// DefaultDeadlineGo is DefaultDeadline's GoInterceptor counterpart. When
+// it fills in a deadline, that deadline must stay live until the call
+// actually finishes rather than until the interceptor returns, so it
+// watches completion on a goroutine and relays the result through a
+// public *rpc.Call of its own, the same pattern TimeoutGo uses.
+func DefaultDeadlineGo(d time.Duration) rpcx.GoInterceptor {
+	return func(ctx context.Context, serviceMethod string, args, reply interface{}, invoker rpcx.GoInvoker) *rpc.Call {
+		if _, ok := ctx.Deadline(); ok {
+			return invoker(ctx, serviceMethod, args, reply)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, d)
+		inner := invoker(ctx, serviceMethod, args, reply)
+
+		public := &rpc.Call{
+			ServiceMethod: serviceMethod,
+			Args:          args,
+			Reply:         reply,
+			Done:          make(chan *rpc.Call, 1), // buffered.
+		}
+		go func() {
+			result := <-inner.Done
+			cancel()
+			public.Error = result.Error
+			public.Done <- public
+		}()
+		return public
+	}
+}
@@ -0,0 +1,139 @@
+// Package middleware provides rpcx.CallInterceptor and rpcx.GoInterceptor
+// implementations for cross-cutting call behavior: retries, timeouts, and
+// circuit breaking. Combine them with rpcx.WithCallInterceptors /
+// rpcx.WithGoInterceptors, which chain multiple interceptors left-to-right
+// into the single interceptor slot a Client dials with.
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"time"
+
+	"github.com/chanxuehong/rpcx"
+)
+
+// RetryPolicy configures Retry. MaxAttempts is the total number of tries,
+// including the first; values <= 1 disable retrying. PerAttemptTimeout,
+// if positive, bounds each individual attempt, carved out of the parent
+// context's deadline rather than replacing it. ShouldRetry decides
+// whether a failed attempt's error warrants another try; nil means
+// defaultShouldRetry.
+type RetryPolicy struct {
+	MaxAttempts       int
+	PerAttemptTimeout time.Duration
+	ShouldRetry       func(error) bool
+}
+
+// retryabler lets an application-level error opt into retries without
+// rpcx or middleware needing to know about it by name.
+type retryabler interface {
+	IsRetryable() bool
+}
+
+func defaultShouldRetry(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, rpcx.ErrShutdown) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var r retryabler
+	if errors.As(err, &r) {
+		return r.IsRetryable()
+	}
+	return false
+}
+
+// Retry returns a CallInterceptor that tries a call up to
+// policy.MaxAttempts times in all, stopping as soon as the call succeeds, the
+// parent context is done, or policy.ShouldRetry rejects the error.
+func Retry(policy RetryPolicy) rpcx.CallInterceptor {
+	shouldRetry := policy.ShouldRetry
+	if shouldRetry == nil {
+		shouldRetry = defaultShouldRetry
+	}
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	return func(ctx context.Context, serviceMethod string, args, reply interface{}, invoker rpcx.CallInvoker) error {
+		var err error
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			attemptCtx := ctx
+			var cancel context.CancelFunc
+			if policy.PerAttemptTimeout > 0 {
+				attemptCtx, cancel = context.WithTimeout(ctx, policy.PerAttemptTimeout)
+			}
+			err = invoker(attemptCtx, serviceMethod, args, reply)
+			if cancel != nil {
+				cancel()
+			}
+			if err == nil {
+				return nil
+			}
+			if attempt == maxAttempts-1 || ctx.Err() != nil || !shouldRetry(err) {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+// RetryGo is Retry's GoInterceptor counterpart: it tries a call up to
+// policy.MaxAttempts times in all, stopping as soon as an attempt
+// succeeds, the parent context is done, or policy.ShouldRetry rejects
+// the error, the same as Retry, but running attempts sequentially on a
+// goroutine so GoInterceptor's non-blocking contract holds even while a
+// retry is in flight.
+func RetryGo(policy RetryPolicy) rpcx.GoInterceptor {
+	shouldRetry := policy.ShouldRetry
+	if shouldRetry == nil {
+		shouldRetry = defaultShouldRetry
+	}
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	return func(ctx context.Context, serviceMethod string, args, reply interface{}, invoker rpcx.GoInvoker) *rpc.Call {
+		public := &rpc.Call{
+			ServiceMethod: serviceMethod,
+			Args:          args,
+			Reply:         reply,
+			Done:          make(chan *rpc.Call, 1), // buffered.
+		}
+		go func() {
+			var attemptCall *rpc.Call
+			for attempt := 0; attempt < maxAttempts; attempt++ {
+				attemptCtx := ctx
+				var cancel context.CancelFunc
+				if policy.PerAttemptTimeout > 0 {
+					attemptCtx, cancel = context.WithTimeout(ctx, policy.PerAttemptTimeout)
+				}
+				attemptCall = invoker(attemptCtx, serviceMethod, args, reply)
+				<-attemptCall.Done
+				if cancel != nil {
+					cancel()
+				}
+				if attemptCall.Error == nil {
+					break
+				}
+				if attempt == maxAttempts-1 || ctx.Err() != nil || !shouldRetry(attemptCall.Error) {
+					break
+				}
+			}
+			public.Error = attemptCall.Error
+			public.Done <- public
+		}()
+		return public
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"net/rpc"
+	"time"
+
+	"github.com/chanxuehong/rpcx"
+)
+
+// Timeout returns a CallInterceptor that bounds each call to d, carved
+// out of the parent context's deadline if it has one and is sooner.
+func Timeout(d time.Duration) rpcx.CallInterceptor {
+	return func(ctx context.Context, serviceMethod string, args, reply interface{}, invoker rpcx.CallInvoker) error {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		return invoker(ctx, serviceMethod, args, reply)
+	}
+}
+
+// TimeoutGo is Timeout's GoInterceptor counterpart: it bounds the call
+// to d the same way. Since the carved context must stay live until the
+// call actually finishes, rather than until the interceptor returns, it
+// watches completion on a goroutine and relays the result through a
+// public *rpc.Call of its own, the same pattern Client.goWatched uses.
+func TimeoutGo(d time.Duration) rpcx.GoInterceptor {
+	return func(ctx context.Context, serviceMethod string, args, reply interface{}, invoker rpcx.GoInvoker) *rpc.Call {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		inner := invoker(ctx, serviceMethod, args, reply)
+
+		public := &rpc.Call{
+			ServiceMethod: serviceMethod,
+			Args:          args,
+			Reply:         reply,
+			Done:          make(chan *rpc.Call, 1), // buffered.
+		}
+		go func() {
+			result := <-inner.Done
+			cancel()
+			public.Error = result.Error
+			public.Done <- public
+		}()
+		return public
+	}
+}
@@ -0,0 +1,41 @@
+// Package peer exposes per-call connection metadata through a
+// context.Context, so a CallInterceptor or GoInterceptor anywhere in a
+// chain built with rpcx.WithCallInterceptors/WithGoInterceptors can
+// inspect which remote address a call is going out on and how much time
+// is left on it, without rpcx threading extra parameters through
+// CallInvoker/GoInvoker.
+package peer
+
+import (
+	"context"
+	"time"
+)
+
+type peerKey struct{}
+
+// Peer describes the connection a call is being made over.
+type Peer struct {
+	// Address is the remote network address the call is issued over.
+	Address string
+
+	// Deadline is the time by which the call is expected to complete,
+	// taken from the context's own deadline at the point NewContext was
+	// called. The zero Time means the call had no deadline.
+	Deadline time.Time
+}
+
+// NewContext returns a copy of ctx carrying a Peer for address, with
+// Deadline taken from ctx.Deadline(), if any.
+func NewContext(ctx context.Context, address string) context.Context {
+	p := &Peer{Address: address}
+	if d, ok := ctx.Deadline(); ok {
+		p.Deadline = d
+	}
+	return context.WithValue(ctx, peerKey{}, p)
+}
+
+// FromContext returns the Peer stored in ctx by NewContext, if any.
+func FromContext(ctx context.Context) (*Peer, bool) {
+	p, ok := ctx.Value(peerKey{}).(*Peer)
+	return p, ok
+}
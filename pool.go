@@ -0,0 +1,367 @@
+package rpcx
+
+import (
+	"context"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// PoolOption configures a Pool at construction time.
+type PoolOption func(*poolOptions)
+
+type poolOptions struct {
+	balancer     Balancer
+	dialOptions  []DialOption
+	pingMethod   string
+	pingInterval time.Duration
+	evictAfter   int
+
+	// logger and nonBlockDialOptions are derived from dialOptions by
+	// resolveDialOptions, so a synchronous WithBlock dial failure can
+	// log through the caller's configured Logger and fall back to a
+	// non-blocking dial that keeps every other option (TLS/transport,
+	// interceptors, backoff, ...) instead of dropping them all.
+	logger              Logger
+	nonBlockDialOptions []DialOption
+}
+
+// resolveDialOptions applies dialOptions once to resolve the Logger the
+// caller configured via WithPoolDialOptions(WithLogger(...)), and builds
+// nonBlockDialOptions: the same options minus WithBlock, for dialing a
+// member non-blockingly after a synchronous WithBlock dial fails. This
+// mirrors class.go:dialClass's sharedOpts, built the same way for the
+// same reason.
+func (o *poolOptions) resolveDialOptions() {
+	var resolved dialOptions
+	for _, opt := range o.dialOptions {
+		opt(&resolved)
+	}
+	o.logger = resolved.logger
+
+	var nonBlock []DialOption
+	if resolved.timeout > 0 {
+		nonBlock = append(nonBlock, WithTimeout(resolved.timeout))
+	}
+	if resolved.logger != nil {
+		nonBlock = append(nonBlock, WithLogger(resolved.logger))
+	}
+	if resolved.transport != nil {
+		nonBlock = append(nonBlock, WithTransport(resolved.transport))
+	}
+	if resolved.backoff != (BackoffPolicy{}) {
+		nonBlock = append(nonBlock, WithReconnectBackoff(resolved.backoff))
+	}
+	if resolved.pingServiceMethod != "" {
+		nonBlock = append(nonBlock, WithHeartbeat(resolved.pingServiceMethod, resolved.pingInterval, resolved.pingHandler))
+	}
+	if resolved.callInterceptor != nil {
+		nonBlock = append(nonBlock, WithCallInterceptor(resolved.callInterceptor))
+	}
+	if resolved.goInterceptor != nil {
+		nonBlock = append(nonBlock, WithGoInterceptor(resolved.goInterceptor))
+	}
+	if len(resolved.classes) > 0 {
+		nonBlock = append(nonBlock, WithConnectionClasses(resolved.classes))
+	}
+	o.nonBlockDialOptions = nonBlock
+}
+
+// WithBalancer sets the Balancer a Pool uses to pick a client per call.
+// The default is a RoundRobin.
+func WithBalancer(b Balancer) PoolOption {
+	return func(o *poolOptions) {
+		if b == nil {
+			return
+		}
+		o.balancer = b
+	}
+}
+
+// WithPoolDialOptions applies opts whenever the Pool dials a sub-client
+// for a newly discovered address.
+func WithPoolDialOptions(opts ...DialOption) PoolOption {
+	return func(o *poolOptions) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// WithPoolHeartbeat makes the Pool itself, rather than the sub-clients,
+// periodically call pingServiceMethod on every member and evict members
+// that fail evictAfter times in a row. An evicted member is not pinged
+// again; it is redialed only when a later resolver update still lists
+// its address. The default is no
+// heartbeat, in which case members are only ever added or removed in
+// response to Resolver updates.
+func WithPoolHeartbeat(pingServiceMethod string, interval time.Duration, evictAfter int) PoolOption {
+	return func(o *poolOptions) {
+		if pingServiceMethod == "" || interval <= 0 {
+			return
+		}
+		if evictAfter <= 0 {
+			evictAfter = 3
+		}
+		o.pingMethod = pingServiceMethod
+		o.pingInterval = interval
+		o.evictAfter = evictAfter
+	}
+}
+
+// Pool maintains a *Client per address returned by a Resolver for a
+// target, reconciling its membership as the resolver reports updates,
+// and spreads calls across its members with a Balancer. It is
+// the multi-backend counterpart to Dial, for service-registry-driven
+// clients that must talk to many endpoints rather than one.
+type Pool struct {
+	target   string
+	resolver Resolver
+	opts     poolOptions
+
+	mutex   sync.RWMutex
+	members map[Address]*poolMember
+	clients []*Client // snapshot of member clients, rebuilt on membership change
+
+	cancel context.CancelFunc
+}
+
+type poolMember struct {
+	address Address
+	client  *Client
+	fails   int
+}
+
+// NewPool resolves target via resolver, dials a *Client for every
+// returned address, and keeps that membership in sync with
+// resolver.Watch until ctx is canceled or Pool.Close is called.
+func NewPool(ctx context.Context, target string, resolver Resolver, opts ...PoolOption) (*Pool, error) {
+	o := poolOptions{balancer: &RoundRobin{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.resolveDialOptions()
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	p := &Pool{
+		target:   target,
+		resolver: resolver,
+		opts:     o,
+		members:  make(map[Address]*poolMember),
+		cancel:   cancel,
+	}
+
+	addrs, err := resolver.Resolve(target)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	p.reconcile(addrs)
+
+	updates, err := resolver.Watch(watchCtx, target)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	go p.watch(watchCtx, updates)
+	if o.pingMethod != "" {
+		go p.heartbeat(watchCtx)
+	}
+
+	return p, nil
+}
+
+func (p *Pool) watch(ctx context.Context, updates <-chan []Address) {
+	for {
+		select {
+		case addrs, ok := <-updates:
+			if !ok {
+				return
+			}
+			p.reconcile(addrs)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Pool) reconcile(addrs []Address) {
+	want := make(map[Address]bool, len(addrs))
+	for _, a := range addrs {
+		want[a] = true
+	}
+
+	p.mutex.RLock()
+	var toDial []Address
+	for addr := range want {
+		if _, ok := p.members[addr]; !ok {
+			toDial = append(toDial, addr)
+		}
+	}
+	p.mutex.RUnlock()
+
+	// Dial new members outside the lock: p.dial can block for as long as
+	// WithPoolDialOptions' WithBlock/WithTimeout allow, and Pool.pick
+	// (used by every Call/Go) only needs a brief RLock to hand out the
+	// current snapshot.
+	dialed := make(map[Address]*Client, len(toDial))
+	for _, addr := range toDial {
+		dialed[addr] = p.dial(addr)
+	}
+
+	p.mutex.Lock()
+	for addr, m := range p.members {
+		if !want[addr] {
+			delete(p.members, addr)
+			m.client.Close()
+		}
+	}
+	for addr, client := range dialed {
+		if _, ok := p.members[addr]; ok {
+			client.Close() // lost a race with a concurrent reconcile; discard
+			continue
+		}
+		p.members[addr] = &poolMember{address: addr, client: client}
+	}
+	p.rebuildLocked()
+	p.mutex.Unlock()
+}
+
+func (p *Pool) dial(addr Address) *Client {
+	client, err := Dial(addr.Network, addr.Addr, p.opts.dialOptions...)
+	if err != nil {
+		// Only a WithBlock dial option can fail synchronously here; fall
+		// back to a non-blocking dial with every other configured option
+		// (TLS/transport, logger, interceptors, backoff, ...) still in
+		// place, so the member still joins the pool - with working
+		// interceptors rather than bypassing them - and reconnects in
+		// the background via Client.Reset.
+		logger := p.opts.logger
+		if logger == nil {
+			logger = defaultLogger
+		}
+		logger.Error("pool: dial failed", "network", addr.Network, "address", addr.Addr, "error", err)
+		client, _ = Dial(addr.Network, addr.Addr, p.opts.nonBlockDialOptions...)
+	}
+	return client
+}
+
+// rebuildLocked refreshes the balancer-visible client snapshot. Callers
+// must hold p.mutex.
+func (p *Pool) rebuildLocked() {
+	clients := make([]*Client, 0, len(p.members))
+	for _, m := range p.members {
+		clients = append(clients, m.client)
+	}
+	p.clients = clients
+}
+
+func (p *Pool) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(p.opts.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.pingMembers()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Pool) pingMembers() {
+	p.mutex.RLock()
+	members := make([]*poolMember, 0, len(p.members))
+	for _, m := range p.members {
+		members = append(members, m)
+	}
+	p.mutex.RUnlock()
+
+	var evicted bool
+	for _, m := range members {
+		ctx, cancel := context.WithTimeout(context.Background(), p.opts.pingInterval)
+		var args, reply struct{}
+		err := m.client.CallContext(ctx, p.opts.pingMethod, &args, &reply)
+		cancel()
+
+		p.mutex.Lock()
+		if _, ok := p.members[m.address]; !ok {
+			p.mutex.Unlock()
+			continue // already reconciled away
+		}
+		if err == nil {
+			m.fails = 0
+		} else {
+			m.fails++
+			if m.fails >= p.opts.evictAfter {
+				delete(p.members, m.address)
+				evicted = true
+				m.client.Close()
+			}
+		}
+		p.mutex.Unlock()
+	}
+
+	if evicted {
+		p.mutex.Lock()
+		p.rebuildLocked()
+		p.mutex.Unlock()
+	}
+}
+
+// Close stops watching for resolver updates and closes every member
+// client.
+func (p *Pool) Close() error {
+	p.cancel()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for addr, m := range p.members {
+		m.client.Close()
+		delete(p.members, addr)
+	}
+	p.clients = nil
+	return nil
+}
+
+func (p *Pool) pick(ctx context.Context, serviceMethod string) (*Client, error) {
+	p.mutex.RLock()
+	clients := p.clients
+	p.mutex.RUnlock()
+	return p.opts.balancer.Pick(ctx, serviceMethod, clients)
+}
+
+// Call picks a client with the Pool's Balancer and issues a synchronous
+// call through it, honoring that client's own CallInterceptor chain.
+func (p *Pool) Call(serviceMethod string, args, reply interface{}) error {
+	return p.CallContext(context.Background(), serviceMethod, args, reply)
+}
+
+func (p *Pool) CallContext(ctx context.Context, serviceMethod string, args, reply interface{}) error {
+	client, err := p.pick(ctx, serviceMethod)
+	if err != nil {
+		return err
+	}
+	return client.CallContext(ctx, serviceMethod, args, reply)
+}
+
+// Go picks a client with the Pool's Balancer and issues an asynchronous
+// call through it, honoring that client's own GoInterceptor chain.
+func (p *Pool) Go(serviceMethod string, args, reply interface{}) *rpc.Call {
+	return p.GoContext(context.Background(), serviceMethod, args, reply)
+}
+
+func (p *Pool) GoContext(ctx context.Context, serviceMethod string, args, reply interface{}) *rpc.Call {
+	client, err := p.pick(ctx, serviceMethod)
+	if err != nil {
+		call := &rpc.Call{
+			ServiceMethod: serviceMethod,
+			Args:          args,
+			Reply:         reply,
+			Error:         err,
+			Done:          make(chan *rpc.Call, 1), // buffered.
+		}
+		call.Done <- call
+		return call
+	}
+	return client.GoContext(ctx, serviceMethod, args, reply)
+}
@@ -0,0 +1,209 @@
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/rpc"
+	"sync/atomic"
+	"time"
+)
+
+// State describes the connectivity of a Client, modeled after gRPC's
+// connectivity.State.
+type State int32
+
+const (
+	Idle State = iota
+	Connecting
+	Ready
+	TransientFailure
+	Shutdown
+)
+
+func (s State) String() string {
+	switch s {
+	case Idle:
+		return "IDLE"
+	case Connecting:
+		return "CONNECTING"
+	case Ready:
+		return "READY"
+	case TransientFailure:
+		return "TRANSIENT_FAILURE"
+	case Shutdown:
+		return "SHUTDOWN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// BackoffPolicy parameterizes the delay between reconnect attempts.
+// Each retry waits Initial*Multiplier^attempt, capped at Max, with up
+// to JitterFraction of that value added or subtracted at random.
+// MaxElapsed bounds the total time spent retrying since the first
+// failure of a reconnect episode; zero means retry forever.
+type BackoffPolicy struct {
+	Initial        time.Duration
+	Max            time.Duration
+	Multiplier     float64
+	JitterFraction float64
+	MaxElapsed     time.Duration
+}
+
+var defaultBackoffPolicy = BackoffPolicy{
+	Initial:        200 * time.Millisecond,
+	Max:            30 * time.Second,
+	Multiplier:     1.6,
+	JitterFraction: 0.2,
+}
+
+// WithReconnectBackoff sets the BackoffPolicy Client uses between
+// reconnect attempts. Zero-valued fields in policy fall back to
+// defaultBackoffPolicy's.
+func WithReconnectBackoff(policy BackoffPolicy) DialOption {
+	return func(o *dialOptions) {
+		if policy.Initial <= 0 {
+			policy.Initial = defaultBackoffPolicy.Initial
+		}
+		if policy.Max <= 0 {
+			policy.Max = defaultBackoffPolicy.Max
+		}
+		if policy.Multiplier <= 1 {
+			policy.Multiplier = defaultBackoffPolicy.Multiplier
+		}
+		if policy.JitterFraction == 0 {
+			policy.JitterFraction = defaultBackoffPolicy.JitterFraction
+		} else if policy.JitterFraction < 0 {
+			policy.JitterFraction = 0
+		}
+		o.backoff = policy
+	}
+}
+
+func (p BackoffPolicy) delay(attempt int) time.Duration {
+	d := float64(p.Initial) * math.Pow(p.Multiplier, float64(attempt))
+	if max := float64(p.Max); d > max {
+		d = max
+	}
+	if p.JitterFraction > 0 {
+		jitter := d * p.JitterFraction
+		d += (rand.Float64()*2 - 1) * jitter
+		if d < 0 {
+			d = 0
+		}
+	}
+	return time.Duration(d)
+}
+
+// State reports the Client's current connectivity state.
+func (client *Client) State() State {
+	return State(atomic.LoadInt32(&client.state))
+}
+
+// WaitForStateChange blocks until the Client's state differs from
+// sourceState or ctx is done, returning false in the latter case.
+func (client *Client) WaitForStateChange(ctx context.Context, sourceState State) bool {
+	client.stateMutex.Lock()
+	if client.State() != sourceState {
+		client.stateMutex.Unlock()
+		return true
+	}
+	ch := client.stateChangeCh
+	client.stateMutex.Unlock()
+
+	select {
+	case <-ch:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (client *Client) setState(s State) {
+	client.stateMutex.Lock()
+	defer client.stateMutex.Unlock()
+	if State(atomic.LoadInt32(&client.state)) == s {
+		return
+	}
+	atomic.StoreInt32(&client.state, int32(s))
+	close(client.stateChangeCh)
+	client.stateChangeCh = make(chan struct{})
+}
+
+// triggerReconnect schedules a reconnect attempt, coalescing with any
+// attempt already pending; reconnectLoop is the only goroutine that
+// ever dials, so at most one dial runs at a time.
+func (client *Client) triggerReconnect() {
+	select {
+	case client.reconnectCh <- struct{}{}:
+	default:
+	}
+}
+
+// reconnectLoop serializes every reconnect attempt for the Client's
+// lifetime. It is woken by triggerReconnect, which is called whenever a
+// Call/Go observes a broken connection, whenever the default heartbeat
+// handler sees a ping fail with a connection error, and by its own
+// backoff timer after a failed attempt.
+func (client *Client) reconnectLoop() {
+	var attempt int
+	var episodeStart time.Time
+
+	for range client.reconnectCh {
+		client.mutex.Lock()
+		closed := client.closed
+		client.mutex.Unlock()
+		if closed {
+			client.setState(Shutdown)
+			return
+		}
+
+		client.setState(Connecting)
+		if attempt == 0 {
+			episodeStart = time.Now()
+		}
+
+		err := client.Reset()
+		if err == nil {
+			client.setState(Ready)
+			attempt = 0
+			continue
+		}
+		if err == rpc.ErrShutdown {
+			client.setState(Shutdown)
+			return
+		}
+
+		client.setState(TransientFailure)
+		backoff := client.dialOptions.backoff
+		if backoff.MaxElapsed > 0 && time.Since(episodeStart) > backoff.MaxElapsed {
+			client.dialOptions.logger.Error("reconnect: giving up",
+				"address", client.dialOptions.address, "elapsed", time.Since(episodeStart), "error", err)
+			attempt = 0
+			continue
+		}
+
+		d := backoff.delay(attempt)
+		attempt++
+		client.dialOptions.logger.Debug("reconnect: attempt failed, retrying",
+			"address", client.dialOptions.address, "attempt", attempt, "delay", d, "error", err)
+		time.AfterFunc(d, client.triggerReconnect)
+	}
+}
+
+// isConnError reports whether err indicates the underlying connection
+// is no longer usable, as opposed to an application-level RPC error.
+func isConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == rpc.ErrShutdown || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
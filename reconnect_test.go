@@ -0,0 +1,120 @@
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStateString(t *testing.T) {
+	cases := map[State]string{
+		Idle:             "IDLE",
+		Connecting:       "CONNECTING",
+		Ready:            "READY",
+		TransientFailure: "TRANSIENT_FAILURE",
+		Shutdown:         "SHUTDOWN",
+		State(99):        "UNKNOWN",
+	}
+	for s, want := range cases {
+		if got := s.String(); got != want {
+			t.Errorf("State(%d).String() = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestBackoffPolicyDelay(t *testing.T) {
+	p := BackoffPolicy{Initial: 10 * time.Millisecond, Max: 100 * time.Millisecond, Multiplier: 2}
+	if d := p.delay(0); d != 10*time.Millisecond {
+		t.Errorf("delay(0) = %v, want 10ms", d)
+	}
+	if d := p.delay(1); d != 20*time.Millisecond {
+		t.Errorf("delay(1) = %v, want 20ms", d)
+	}
+	if d := p.delay(10); d != 100*time.Millisecond {
+		t.Errorf("delay(10) = %v, want capped at 100ms", d)
+	}
+}
+
+// fakeTransport fails its first `fail` dials, then hands out a net.Pipe
+// connection for every subsequent one.
+type fakeTransport struct {
+	mu   sync.Mutex
+	fail int
+}
+
+func (t *fakeTransport) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fail > 0 {
+		t.fail--
+		return nil, errors.New("fake: dial refused")
+	}
+	server, client := net.Pipe()
+	go io.Copy(io.Discard, server) // drain so the rpc.Client side never blocks on Write
+	return client, nil
+}
+
+func TestDialBlockFailureDoesNotLeakReconnectLoop(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	_, err := Dial("tcp", "example.invalid:1", WithBlock(), WithTransport(&fakeTransport{fail: 1}))
+	if err == nil {
+		t.Fatal("expected Dial to fail")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutine count did not settle back to %d: now %d", before, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReconnectLoopTransitionsToReady(t *testing.T) {
+	client, err := Dial("tcp", "example.invalid:1",
+		WithTransport(&fakeTransport{fail: 2}),
+		WithReconnectBackoff(BackoffPolicy{Initial: time.Millisecond, Max: 5 * time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer client.Close()
+
+	if client.State() != Connecting && client.State() != TransientFailure {
+		t.Fatalf("initial state = %v, want Connecting or TransientFailure", client.State())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for client.State() != Ready {
+		if !client.WaitForStateChange(ctx, client.State()) {
+			t.Fatalf("timed out waiting for Ready, last state %v", client.State())
+		}
+	}
+}
+
+func TestCloseUnblocksReconnectLoop(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	client, err := Dial("tcp", "example.invalid:1", WithTransport(&fakeTransport{fail: 1000}))
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if err := client.Close(); err != nil && !errors.Is(err, ErrShutdown) {
+		t.Fatalf("Close: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutine count did not settle back to %d: now %d", before, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,41 @@
+package rpcx
+
+import "context"
+
+// Address identifies a single dialable backend returned by a Resolver.
+type Address struct {
+	Network string
+	Addr    string
+}
+
+// Resolver discovers the set of backend addresses for a target, such as
+// a service name in a service registry, and notifies watchers whenever
+// that set changes.
+type Resolver interface {
+	// Resolve returns the current set of addresses for target.
+	Resolve(target string) ([]Address, error)
+
+	// Watch returns a channel that receives the full, updated address
+	// set every time it changes. The channel is closed once ctx is
+	// done; callers should stop watching when that happens.
+	Watch(ctx context.Context, target string) (<-chan []Address, error)
+}
+
+// StaticResolver resolves any target to a fixed list of addresses. It
+// never sends updates, which makes it suitable for users who just want
+// manual failover across a known set of backends rather than a real
+// service registry.
+type StaticResolver []Address
+
+func (r StaticResolver) Resolve(target string) ([]Address, error) {
+	return []Address(r), nil
+}
+
+func (r StaticResolver) Watch(ctx context.Context, target string) (<-chan []Address, error) {
+	ch := make(chan []Address)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
@@ -0,0 +1,106 @@
+package rpcx
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Transport dials the underlying connection a Client reconnects over.
+// The default, used when no WithTransport-family DialOption is given,
+// is a plain TCP transport matching Client's historical behavior.
+type Transport interface {
+	Dial(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// WithTransport selects the Transport a Client dials its connection
+// with.
+func WithTransport(t Transport) DialOption {
+	return func(o *dialOptions) {
+		if t == nil {
+			return
+		}
+		o.transport = t
+	}
+}
+
+// tcpTransport is the default Transport.
+type tcpTransport struct{}
+
+func (tcpTransport) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := net.Dialer{KeepAlive: 30 * time.Second}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// tlsTransport dials a plain connection and performs a TLS handshake
+// over it using config.
+type tlsTransport struct {
+	config *tls.Config
+}
+
+func (t tlsTransport) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := net.Dialer{KeepAlive: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, config(t.config, address))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func config(c *tls.Config, address string) *tls.Config {
+	if c == nil {
+		c = &tls.Config{}
+	}
+	if c.ServerName != "" {
+		return c
+	}
+	c = c.Clone()
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		c.ServerName = host
+	}
+	return c
+}
+
+// WithTLS selects a Transport that dials a plain connection and then
+// performs a TLS handshake over it using config. This lets a Client
+// talk to rpcx servers sitting behind a TLS-terminating proxy or load
+// balancer, or that speak TLS directly.
+func WithTLS(config *tls.Config) DialOption {
+	return WithTransport(tlsTransport{config: config})
+}
+
+// WithWebSocket selects a Transport that dials a plain TCP connection,
+// performs an HTTP upgrade to a WebSocket (RFC 6455) at path, and
+// frames the RPC byte stream as binary WebSocket messages. header is
+// sent with the upgrade request and may be nil. This lets a Client
+// reach rpcx servers only reachable over browser-compatible endpoints,
+// e.g. behind an HTTP reverse proxy. The connection is never wrapped in
+// TLS, so HTTPS (wss://) endpoints are not supported.
+func WithWebSocket(path string, header http.Header) DialOption {
+	return WithTransport(websocketTransport{path: path, header: header})
+}
+
+type websocketTransport struct {
+	path   string
+	header http.Header
+}
+
+func (t websocketTransport) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := (tcpTransport{}).Dial(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	wsConn, err := websocketUpgrade(ctx, conn, address, t.path, t.header)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return wsConn, nil
+}
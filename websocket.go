@@ -0,0 +1,227 @@
+package rpcx
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// websocketGUID is the fixed GUID RFC 6455 uses to compute
+// Sec-WebSocket-Accept from the client's Sec-WebSocket-Key.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpContinuation byte = 0x0
+	wsOpBinary       byte = 0x2
+	wsOpClose        byte = 0x8
+	wsOpPing         byte = 0x9
+	wsOpPong         byte = 0xA
+)
+
+// websocketUpgrade performs the RFC 6455 client handshake over conn and,
+// on success, returns a net.Conn that frames Read/Write as binary
+// WebSocket messages, suitable for rpc.NewClient. Like tlsTransport's
+// handshake, it honors ctx: a deadline on ctx bounds the handshake I/O,
+// and cancelling ctx for any other reason aborts it by closing conn.
+func websocketUpgrade(ctx context.Context, conn net.Conn, address, path string, header http.Header) (net.Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	keyBytes := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, keyBytes); err != nil {
+		return nil, err
+	}
+	secKey := base64.StdEncoding.EncodeToString(keyBytes)
+
+	hdr := header.Clone()
+	if hdr == nil {
+		hdr = make(http.Header)
+	}
+	hdr.Set("Upgrade", "websocket")
+	hdr.Set("Connection", "Upgrade")
+	hdr.Set("Sec-WebSocket-Key", secKey)
+	hdr.Set("Sec-WebSocket-Version", "13")
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: path},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     hdr,
+		Host:       address,
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("rpcx: websocket: unexpected handshake status %q", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != websocketAcceptKey(secKey) {
+		return nil, errors.New("rpcx: websocket: invalid Sec-WebSocket-Accept")
+	}
+
+	return &websocketConn{Conn: conn, br: br}, nil
+}
+
+func websocketAcceptKey(secKey string) string {
+	h := sha1.New()
+	h.Write([]byte(secKey))
+	h.Write([]byte(websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// websocketConn adapts a WebSocket connection to net.Conn by framing
+// every Write as a single binary message and transparently unwrapping
+// binary messages (and control frames) on Read, so callers see a plain
+// byte stream.
+type websocketConn struct {
+	net.Conn
+	br      *bufio.Reader // buffers any bytes read ahead during the handshake
+	payload []byte        // unread bytes of the current incoming message
+}
+
+func (c *websocketConn) Read(p []byte) (int, error) {
+	for len(c.payload) == 0 {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, err
+		}
+		switch opcode {
+		case wsOpBinary, wsOpContinuation:
+			c.payload = payload
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return 0, err
+			}
+		case wsOpPong:
+			// nothing to do
+		case wsOpClose:
+			return 0, io.EOF
+		default:
+			return 0, fmt.Errorf("rpcx: websocket: unsupported opcode %#x", opcode)
+		}
+	}
+	n := copy(p, c.payload)
+	c.payload = c.payload[n:]
+	return n, nil
+}
+
+// readFrame reads one WebSocket frame and returns its opcode and
+// unmasked payload. Server-to-client frames must not be masked.
+func (c *websocketConn) readFrame() (opcode byte, payload []byte, err error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(c.br, head); err != nil {
+		return 0, nil, err
+	}
+	opcode = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(c.br, ext); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(c.br, ext); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, maskKey[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= maskKey[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
+
+func (c *websocketConn) Write(p []byte) (int, error) {
+	if err := c.writeFrame(wsOpBinary, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeFrame writes a single, final, masked WebSocket frame, as RFC
+// 6455 requires of every client-to-server frame.
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	var maskKey [4]byte
+	if _, err := io.ReadFull(rand.Reader, maskKey[:]); err != nil {
+		return err
+	}
+
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|opcode) // FIN=1, single frame
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	frame = append(frame, maskKey[:]...)
+
+	masked := make([]byte, length)
+	for i, b := range payload {
+		masked[i] = b ^ maskKey[i%4]
+	}
+	frame = append(frame, masked...)
+
+	_, err := c.Conn.Write(frame)
+	return err
+}
+
+func (c *websocketConn) Close() error {
+	_ = c.writeFrame(wsOpClose, nil)
+	return c.Conn.Close()
+}